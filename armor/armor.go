@@ -0,0 +1,150 @@
+// Package armor provides a signify-style armored text encoding for
+// gabby-grove refs, so they can be shared out-of-band over channels that
+// only carry plain text (email, git, pastebins). Events have no armored
+// form here: only refs can be encoded and decoded.
+//
+// The layout mirrors OpenBSD signify's b64file:
+//
+//	untrusted comment: <free text>
+//	<standard-base64 of the binary payload>
+//	<optional detached signature, also standard-base64>
+package armor
+
+import (
+	"bufio"
+	"encoding/base64"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	gabbygrove "go.mindeco.de/gabbygrove"
+)
+
+// untrustedPrefix is the mandatory first line of an armored file. It is
+// called "untrusted" because, like in signify, the comment is never
+// authenticated - only the payload (and, if present, the signature) is.
+const untrustedPrefix = "untrusted comment: "
+
+// maxCommentLen bounds the first line so a hostile file can't make decoders
+// buffer an unbounded amount of text before they even look at the payload.
+const maxCommentLen = 1024
+
+// ErrUntrusted is returned by DecodeRef when the first line of the
+// input doesn't start with the expected "untrusted comment: " prefix.
+var ErrUntrusted = errors.New("armor: missing 'untrusted comment:' header")
+
+// EncodeRef writes r to w in armored form, with comment as the free-text
+// first line.
+func EncodeRef(w io.Writer, comment string, r gabbygrove.BinaryRef) error {
+	payload, err := r.MarshalBinary()
+	if err != nil {
+		return errors.Wrap(err, "armor: marshal ref")
+	}
+	return encode(w, comment, payload, nil)
+}
+
+// DecodeRef reads an armored ref previously written by EncodeRef.
+func DecodeRef(r io.Reader) (string, gabbygrove.BinaryRef, error) {
+	var ref gabbygrove.BinaryRef
+	comment, payload, _, err := decode(r)
+	if err != nil {
+		return "", ref, err
+	}
+	if err := ref.UnmarshalBinary(payload); err != nil {
+		return "", ref, errors.Wrap(err, "armor: unmarshal ref")
+	}
+	return comment, ref, nil
+}
+
+// There is no EncodeEvent/DecodeEvent: this package has no armored form
+// for events, only for refs.
+
+// encode writes the three-line (or two-line, if sig is nil) armored format.
+func encode(w io.Writer, comment string, payload, sig []byte) error {
+	if len(comment) > maxCommentLen {
+		return errors.Errorf("armor: comment too long (%d > %d)", len(comment), maxCommentLen)
+	}
+	if _, err := io.WriteString(w, untrustedPrefix+comment+"\n"); err != nil {
+		return err
+	}
+	enc := base64.StdEncoding.EncodeToString(payload)
+	if _, err := io.WriteString(w, enc+"\n"); err != nil {
+		return err
+	}
+	if sig != nil {
+		if _, err := io.WriteString(w, base64.StdEncoding.EncodeToString(sig)+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decode parses the armored format back into its comment, payload and an
+// optional detached signature (nil if the file only has two lines).
+func decode(r io.Reader) (comment string, payload, sig []byte, err error) {
+	br := bufio.NewReader(r)
+
+	// Only the comment line is capped here: it's read byte-by-byte so a
+	// hostile file can't force us to buffer an unbounded line before we
+	// even see whether it has a '\n'. The payload/signature lines below
+	// are read with the ordinary buffered ReadString/ReadAll, since a real
+	// armored event's base64 body can legitimately be many KB.
+	line1, err := readBoundedLine(br, len(untrustedPrefix)+maxCommentLen)
+	if err != nil {
+		return "", nil, nil, errors.Wrap(err, "armor: reading comment line")
+	}
+	if !strings.HasPrefix(line1, untrustedPrefix) {
+		return "", nil, nil, ErrUntrusted
+	}
+	comment = line1[len(untrustedPrefix):]
+
+	line2, err := br.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", nil, nil, errors.Wrap(err, "armor: reading payload line")
+	}
+	line2 = strings.TrimSuffix(line2, "\n")
+	payload, err = base64.StdEncoding.DecodeString(line2)
+	if err != nil {
+		return "", nil, nil, errors.Wrap(err, "armor: invalid base64 payload (truncated?)")
+	}
+
+	rest, err := ioutil.ReadAll(br)
+	if err != nil {
+		return "", nil, nil, errors.Wrap(err, "armor: reading signature line")
+	}
+	line3 := strings.TrimSuffix(string(rest), "\n")
+	if line3 != "" {
+		sig, err = base64.StdEncoding.DecodeString(line3)
+		if err != nil {
+			return "", nil, nil, errors.Wrap(err, "armor: invalid base64 signature")
+		}
+	}
+
+	return comment, payload, sig, nil
+}
+
+// readBoundedLine reads up to '\n' (exclusive) or EOF, erroring out once
+// more than max bytes have been read without finding one. Unlike
+// bufio.Reader.ReadString, it never buffers past that bound.
+func readBoundedLine(br *bufio.Reader, max int) (string, error) {
+	var line []byte
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return "", err
+		}
+		if b == '\n' {
+			break
+		}
+		line = append(line, b)
+		if len(line) > max {
+			return "", errors.Errorf("line exceeds %d bytes", max)
+		}
+	}
+	return string(line), nil
+}
@@ -0,0 +1,129 @@
+package armor
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ed25519"
+
+	gabbygrove "go.mindeco.de/gabbygrove"
+	refs "go.mindeco.de/ssb-refs"
+)
+
+func testFeedRef(t testing.TB) gabbygrove.BinaryRef {
+	t.Helper()
+	pub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fr, err := refs.NewFeedRefFromBytes(pub, refs.RefAlgoFeedGabby)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref, err := gabbygrove.NewBinaryRef(fr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ref
+}
+
+func TestRefRoundtrip(t *testing.T) {
+	ref := testFeedRef(t)
+
+	var buf bytes.Buffer
+	if err := EncodeRef(&buf, "a test feed", ref); err != nil {
+		t.Fatal(err)
+	}
+
+	gotComment, gotRef, err := DecodeRef(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotComment != "a test feed" {
+		t.Errorf("comment: got %q", gotComment)
+	}
+	if gotRef.Ref() != ref.Ref() {
+		t.Errorf("ref: got %q, want %q", gotRef.Ref(), ref.Ref())
+	}
+}
+
+func TestDecodeMalformedHeader(t *testing.T) {
+	cases := []struct {
+		name string
+		in   string
+	}{
+		{"missing header entirely", "bm90IGFuIGFybW9yZWQgZmlsZQ==\n"},
+		{"wrong prefix", "trusted comment: nope\nbm90IGFuIGFybW9yZWQgZmlsZQ==\n"},
+		{"empty input", ""},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, _, err := DecodeRef(strings.NewReader(tc.in)); err != ErrUntrusted {
+				t.Errorf("got err %v, want ErrUntrusted", err)
+			}
+		})
+	}
+}
+
+func TestDecodeTruncatedBase64(t *testing.T) {
+	in := "untrusted comment: truncated\nAQ==not-valid-base64!!!\n"
+	if _, _, err := DecodeRef(strings.NewReader(in)); err == nil {
+		t.Fatal("expected an error decoding truncated base64, got nil")
+	}
+}
+
+func TestDecodeCommentTooLong(t *testing.T) {
+	in := "untrusted comment: " + strings.Repeat("x", maxCommentLen+1) + "\n"
+	if _, _, err := DecodeRef(strings.NewReader(in)); err == nil {
+		t.Fatal("expected an error decoding an oversized comment, got nil")
+	}
+}
+
+// FuzzDecodeRef seeds from the malformed-header/truncated-base64 cases
+// above plus a valid armored ref, then hands decode() arbitrary mutations
+// of them. decode() must never panic, regardless of how garbled the input
+// is - only return an error.
+func FuzzDecodeRef(f *testing.F) {
+	f.Add([]byte("bm90IGFuIGFybW9yZWQgZmlsZQ==\n"))
+	f.Add([]byte("trusted comment: nope\nbm90IGFuIGFybW9yZWQgZmlsZQ==\n"))
+	f.Add([]byte(""))
+	f.Add([]byte("untrusted comment: truncated\nAQ==not-valid-base64!!!\n"))
+	f.Add([]byte("untrusted comment: " + strings.Repeat("x", maxCommentLen+1) + "\n"))
+
+	var buf bytes.Buffer
+	if err := EncodeRef(&buf, "seed", testFeedRef(f)); err != nil {
+		f.Fatal(err)
+	}
+	f.Add(buf.Bytes())
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, _, _, _ = decode(bytes.NewReader(data))
+	})
+}
+
+func TestDecodeLargePayloadNotTruncated(t *testing.T) {
+	// A real armored event's base64 body (a CBOR-encoded Transfer) can
+	// legitimately run to several KB, well past the old
+	// io.LimitReader(r, maxCommentLen*4) bound that wrapped the whole
+	// stream. Build one directly with encode(), bypassing EncodeRef, so
+	// the test doesn't need a real large payload type.
+	bigPayload := bytes.Repeat([]byte("event-bytes"), 1024) // ~11KB
+
+	var buf bytes.Buffer
+	if err := encode(&buf, "a large payload", bigPayload, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	gotComment, gotPayload, _, err := decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotComment != "a large payload" {
+		t.Errorf("comment: got %q", gotComment)
+	}
+	if !bytes.Equal(gotPayload, bigPayload) {
+		t.Errorf("payload got truncated: len(got)=%d len(want)=%d", len(gotPayload), len(bigPayload))
+	}
+}
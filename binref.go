@@ -1,7 +1,10 @@
 package gabbygrove
 
 import (
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/pkg/errors"
 	"github.com/ugorji/go/codec"
@@ -19,16 +22,132 @@ const (
 	RefTypeContent
 )
 
+// RefAlgo is the on-the-wire tag of a concrete binary ref algorithm,
+// registered for a particular RefType via RegisterRefAlgo.
+type RefAlgo byte
+
+// RefAlgoEd25519SHA256 is the original gabby-grove algorithm: an ed25519
+// public key for feed refs, or a SHA-256 digest for message/content refs.
+const RefAlgoEd25519SHA256 RefAlgo = 0x01
+
+// NewRefFunc builds a BinaryRef of the registered kind from its raw payload.
+type NewRefFunc func(payload []byte) (BinaryRef, error)
+
+// VerifyRefFunc checks a ref against arbitrary data, e.g. a signature or a
+// hash preimage. Algorithms that don't support verification may leave it nil.
+type VerifyRefFunc func(ref BinaryRef, data []byte) error
+
+// SignRefFunc produces a fresh BinaryRef (and signature, where applicable)
+// for data under priv. Algorithms that don't support signing may leave it nil.
+type SignRefFunc func(priv ed25519.PrivateKey, data []byte) (ref BinaryRef, sig []byte, err error)
+
+// refAlgo bundles everything the registry needs to (un)marshal and, where
+// supported, verify or sign a BinaryRef for one (kind, algo) pair.
+type refAlgo struct {
+	payloadLen int
+	newRef     NewRefFunc
+	verify     VerifyRefFunc
+	sign       SignRefFunc
+
+	// jsonSuffix is the text after the last '.' in the sigil form produced
+	// by MarshalJSON, e.g. "ed25519" or "sha256". It is how UnmarshalJSON
+	// maps a sigil ref back to the algo that should decode it.
+	jsonSuffix string
+}
+
+var refRegistry = map[RefType]map[RefAlgo]refAlgo{}
+
+// RegisterRefAlgo registers a binary ref algorithm for kind (feed, message
+// or content), keyed by its wire tag algo. jsonSuffix is the text after the
+// last '.' in the sigil form (e.g. "ed25519"), used to route
+// BinaryRef.UnmarshalJSON back to this algo. It mirrors go-amino's
+// RegisterInterface/RegisterConcrete: call it from an init() before any
+// affected BinaryRef is marshalled or unmarshalled. verify and sign may be
+// nil if the algorithm doesn't support that operation. Registering the same
+// (kind, algo) pair twice panics.
+func RegisterRefAlgo(kind RefType, algo RefAlgo, jsonSuffix string, payloadLen int, newRef NewRefFunc, verify VerifyRefFunc, sign SignRefFunc) {
+	m, ok := refRegistry[kind]
+	if !ok {
+		m = make(map[RefAlgo]refAlgo)
+		refRegistry[kind] = m
+	}
+	if _, taken := m[algo]; taken {
+		panic(fmt.Sprintf("gabbygrove: ref algo %x already registered for kind %d", algo, kind))
+	}
+	m[algo] = refAlgo{
+		payloadLen: payloadLen,
+		newRef:     newRef,
+		verify:     verify,
+		sign:       sign,
+		jsonSuffix: jsonSuffix,
+	}
+}
+
+func lookupRefAlgo(kind RefType, algo RefAlgo) (refAlgo, error) {
+	m, ok := refRegistry[kind]
+	if !ok {
+		return refAlgo{}, errors.Errorf("binref: no algorithms registered for kind %d", kind)
+	}
+	a, ok := m[algo]
+	if !ok {
+		return refAlgo{}, errors.Errorf("binref: unknown algo %x for kind %d", algo, kind)
+	}
+	return a, nil
+}
+
+// lookupRefAlgoBySuffix finds the algo registered for kind whose jsonSuffix
+// matches the trailing ".<suffix>" of a sigil-style ref string.
+func lookupRefAlgoBySuffix(kind RefType, suffix string) (RefAlgo, refAlgo, error) {
+	for algo, a := range refRegistry[kind] {
+		if a.jsonSuffix == suffix {
+			return algo, a, nil
+		}
+	}
+	return 0, refAlgo{}, errors.Errorf("binref: no algo registered for kind %d with suffix %q", kind, suffix)
+}
+
+func init() {
+	RegisterRefAlgo(RefTypeFeed, RefAlgoEd25519SHA256, fmt.Sprintf("%s", refs.RefAlgoFeedGabby), ed25519.PublicKeySize,
+		func(payload []byte) (BinaryRef, error) {
+			fr, err := refs.NewFeedRefFromBytes(payload, refs.RefAlgoFeedGabby)
+			if err != nil {
+				return BinaryRef{}, err
+			}
+			return BinaryRef{fr: &fr, algo: RefAlgoEd25519SHA256}, nil
+		}, nil, nil)
+
+	RegisterRefAlgo(RefTypeMessage, RefAlgoEd25519SHA256, fmt.Sprintf("%s", refs.RefAlgoMessageGabby), 32,
+		func(payload []byte) (BinaryRef, error) {
+			mr, err := refs.NewMessageRefFromBytes(payload, refs.RefAlgoMessageGabby)
+			if err != nil {
+				return BinaryRef{}, err
+			}
+			return BinaryRef{mr: &mr, algo: RefAlgoEd25519SHA256}, nil
+		}, nil, nil)
+
+	RegisterRefAlgo(RefTypeContent, RefAlgoEd25519SHA256, fmt.Sprintf("%s", RefAlgoContentGabby), 32,
+		func(payload []byte) (BinaryRef, error) {
+			var cr ContentRef
+			if err := cr.UnmarshalBinary(append([]byte{0x02}, payload...)); err != nil {
+				return BinaryRef{}, err
+			}
+			if cr.Algo() != RefAlgoContentGabby {
+				return BinaryRef{}, errors.Errorf("unmarshal: invalid binary content ref for feed: %q", cr.algo)
+			}
+			return BinaryRef{cr: &cr, algo: RefAlgoEd25519SHA256}, nil
+		}, nil, nil)
+}
+
 // BinaryRef defines a binary representation for feed, message, and content references
 type BinaryRef struct {
 	fr *refs.FeedRef
 	mr *refs.MessageRef
 	cr *ContentRef // payload/content ref
-}
 
-// currently all references are 32bytes long
-// one additional byte for tagging the type
-const binrefSize = 33
+	// algo is the registered algorithm this ref was built with. It defaults
+	// to RefAlgoEd25519SHA256 for refs constructed outside of UnmarshalBinary.
+	algo RefAlgo
+}
 
 func (ref BinaryRef) valid() (RefType, error) {
 	i := 0
@@ -68,19 +187,23 @@ func (ref BinaryRef) MarshalBinary() ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
+	algo := ref.algo
+	if algo == 0 {
+		algo = RefAlgoEd25519SHA256
+	}
 	switch t {
 	case RefTypeFeed:
-		return append([]byte{0x01}, ref.fr.PubKey()...), nil
+		return append([]byte{byte(RefTypeFeed), byte(algo)}, ref.fr.PubKey()...), nil
 	case RefTypeMessage:
 		hd := make([]byte, 32)
 		err := ref.mr.CopyHashTo(hd)
-		return append([]byte{0x02}, hd...), err
+		return append([]byte{byte(RefTypeMessage), byte(algo)}, hd...), err
 	case RefTypeContent:
 		if ref.cr.algo != RefAlgoContentGabby {
 			return nil, errors.Errorf("invalid binary content ref for feed: %s", ref.cr.algo)
 		}
 		crBytes, err := ref.cr.MarshalBinary()
-		return append([]byte{0x03}, crBytes[1:]...), err
+		return append([]byte{byte(RefTypeContent), byte(algo)}, crBytes[1:]...), err
 	default:
 		// TODO: check if nil!?
 		return nil, nil
@@ -88,39 +211,55 @@ func (ref BinaryRef) MarshalBinary() ([]byte, error) {
 }
 
 func (ref *BinaryRef) UnmarshalBinary(data []byte) error {
-	if n := len(data); n != binrefSize {
+	if n := len(data); n < 2 {
 		return errors.Errorf("binref: invalid len:%d", n)
 	}
-	switch data[0] {
-	case 0x01:
-		fr, err := refs.NewFeedRefFromBytes(data[1:], refs.RefAlgoFeedGabby)
-		if err != nil {
-			return err
-		}
-		ref.fr = &fr
-	case 0x02:
-		mr, err := refs.NewMessageRefFromBytes(data[1:], refs.RefAlgoMessageGabby)
-		if err != nil {
-			return err
-		}
-		ref.mr = &mr
-	case 0x03:
-		var newCR ContentRef
-		if err := newCR.UnmarshalBinary(append([]byte{0x02}, data[1:]...)); err != nil {
-			return err
-		}
-		if newCR.Algo() != RefAlgoContentGabby {
-			return errors.Errorf("unmarshal: invalid binary content ref for feed: %q", newCR.algo)
-		}
-		ref.cr = &newCR
-	default:
-		return fmt.Errorf("unmarshal: invalid binref type: %x", data[0])
+	kind := RefType(data[0])
+	algo := RefAlgo(data[1])
+
+	a, err := lookupRefAlgo(kind, algo)
+	if err != nil {
+		return err
 	}
+
+	payload := data[2:]
+	if n := len(payload); n != a.payloadLen {
+		return errors.Errorf("binref: invalid payload len for kind %d algo %x: %d", kind, algo, n)
+	}
+
+	newRef, err := a.newRef(payload)
+	if err != nil {
+		return err
+	}
+	// Set explicitly rather than trusting newRef to embed it: a
+	// RegisterRefAlgo caller that forgets to stamp .algo on its returned
+	// BinaryRef would otherwise unmarshal into a zero-value algo, which
+	// MarshalBinary/Size then silently default back to
+	// RefAlgoEd25519SHA256, corrupting the wire tag on round-trip.
+	newRef.algo = algo
+	*ref = newRef
 	return nil
 }
 
-func (ref *BinaryRef) Size() int {
-	return binrefSize
+// Size returns the encoded length of this particular ref, which depends on
+// the algorithm it was built with.
+func (ref *BinaryRef) Size() (int, error) {
+	t, err := ref.valid()
+	if err != nil {
+		return 0, err
+	}
+	if t == RefTypeUndefined {
+		return 0, errors.Errorf("binref: Size of an undefined ref")
+	}
+	algo := ref.algo
+	if algo == 0 {
+		algo = RefAlgoEd25519SHA256
+	}
+	a, err := lookupRefAlgo(t, algo)
+	if err != nil {
+		return 0, err
+	}
+	return 2 + a.payloadLen, nil
 }
 
 func (ref BinaryRef) MarshalJSON() ([]byte, error) {
@@ -140,9 +279,72 @@ func bytestr(r refs.Ref) []byte {
 	return []byte("\"" + r.Ref() + "\"")
 }
 
+// ErrUnknownSigil is returned by BinaryRef.UnmarshalJSON when a sigil-style
+// ref string doesn't match any algorithm registered via RegisterRefAlgo.
+type ErrUnknownSigil struct {
+	Sigil string
+}
+
+func (e ErrUnknownSigil) Error() string {
+	return fmt.Sprintf("binref: unknown sigil ref: %q", e.Sigil)
+}
+
+// sigil kinds understood by UnmarshalJSON, in the order they should be tried
+// for a given leading byte. '%' is shared by message and content refs, so
+// both kinds are tried and disambiguated by the algo suffix.
+var sigilKinds = map[byte][]RefType{
+	'@': {RefTypeFeed},
+	'%': {RefTypeMessage, RefTypeContent},
+}
+
 func (ref *BinaryRef) UnmarshalJSON(data []byte) error {
-	// spew.Dump(string(data))
-	return errors.Errorf("TODO:json")
+	var sigil string
+	if err := json.Unmarshal(data, &sigil); err != nil {
+		return errors.Wrap(err, "binref: expected a JSON string")
+	}
+
+	if len(sigil) < 2 {
+		return ErrUnknownSigil{Sigil: sigil}
+	}
+
+	kinds, ok := sigilKinds[sigil[0]]
+	if !ok {
+		return ErrUnknownSigil{Sigil: sigil}
+	}
+
+	dot := strings.LastIndexByte(sigil, '.')
+	if dot < 0 {
+		return ErrUnknownSigil{Sigil: sigil}
+	}
+	payload, err := base64.StdEncoding.DecodeString(sigil[1:dot])
+	if err != nil {
+		return errors.Wrapf(err, "binref: invalid base64 in sigil ref %q", sigil)
+	}
+	suffix := sigil[dot+1:]
+
+	var algo RefAlgo
+	var a refAlgo
+	for _, kind := range kinds {
+		algo, a, err = lookupRefAlgoBySuffix(kind, suffix)
+		if err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return ErrUnknownSigil{Sigil: sigil}
+	}
+
+	if n := len(payload); n != a.payloadLen {
+		return errors.Errorf("binref: invalid payload len for %q: %d", sigil, n)
+	}
+
+	newRef, err := a.newRef(payload)
+	if err != nil {
+		return errors.Wrapf(err, "binref: decoding %q", sigil)
+	}
+	newRef.algo = algo
+	*ref = newRef
+	return nil
 }
 
 func (ref BinaryRef) GetRef(t RefType) (refs.Ref, error) {
@@ -175,6 +377,7 @@ func NewBinaryRef(r refs.Ref) (BinaryRef, error) {
 
 func fromRef(r refs.Ref) (BinaryRef, error) {
 	var br BinaryRef
+	br.algo = RefAlgoEd25519SHA256
 	switch tr := r.(type) {
 	case refs.FeedRef:
 		br.fr = &tr
@@ -194,7 +397,8 @@ func refFromPubKey(pk ed25519.PublicKey) (*BinaryRef, error) {
 	}
 	fr, err := refs.NewFeedRefFromBytes(pk, refs.RefAlgoFeedGabby)
 	return &BinaryRef{
-		fr: &fr,
+		fr:   &fr,
+		algo: RefAlgoEd25519SHA256,
 	}, err
 }
 
@@ -0,0 +1,81 @@
+package gabbygrove
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"golang.org/x/crypto/ed25519"
+
+	refs "go.mindeco.de/ssb-refs"
+)
+
+func TestBinaryRefJSONRoundtrip(t *testing.T) {
+	pub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fr, err := refs.NewFeedRefFromBytes(pub, refs.RefAlgoFeedGabby)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := sha256.Sum256([]byte("some message"))
+	mr, err := refs.NewMessageRefFromBytes(h[:], refs.RefAlgoMessageGabby)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name string
+		ref  refs.Ref
+	}{
+		{"feed", fr},
+		{"message", mr},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			want, err := NewBinaryRef(tc.ref)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			data, err := want.MarshalJSON()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got BinaryRef
+			if err := got.UnmarshalJSON(data); err != nil {
+				t.Fatalf("UnmarshalJSON(%s): %v", data, err)
+			}
+
+			if got.Ref() != want.Ref() {
+				t.Errorf("roundtrip ref mismatch: got %q want %q", got.Ref(), want.Ref())
+			}
+		})
+	}
+}
+
+func TestBinaryRefUnmarshalJSONRejectsUnknownSigil(t *testing.T) {
+	cases := []struct {
+		name string
+		in   string
+	}{
+		{"unknown sigil char", `"!notasigil.ed25519"`},
+		{"missing suffix", `"@bm90aHRlcg=="`},
+		{"unknown suffix", `"@bm90aHRlcg==.nonsense-algo"`},
+		{"too short", `"@"`},
+		{"not json string", `{}`},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var ref BinaryRef
+			err := ref.UnmarshalJSON([]byte(tc.in))
+			if err == nil {
+				t.Fatalf("expected an error unmarshaling %s, got nil", tc.in)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package gabbygrove
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// RefStreamWriter frames a sequence of BinaryRefs with an unsigned-varint
+// length prefix, matching amino's MarshalBinaryLengthPrefixed convention.
+// Since BinaryRef no longer has a fixed wire size (see the ref algorithm
+// registry), this lets callers concatenate heterogeneous refs into
+// log-replication streams or offer-lists without wrapping them in an outer
+// CBOR array.
+type RefStreamWriter struct {
+	w io.Writer
+}
+
+// NewRefStreamWriter returns a RefStreamWriter that writes framed refs to w.
+func NewRefStreamWriter(w io.Writer) *RefStreamWriter {
+	return &RefStreamWriter{w: w}
+}
+
+// WriteRef marshals ref and writes it as one length-prefixed frame.
+func (rw *RefStreamWriter) WriteRef(ref BinaryRef) error {
+	data, err := ref.MarshalBinary()
+	if err != nil {
+		return errors.Wrap(err, "refstream: marshal ref")
+	}
+
+	var lenBuf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(lenBuf[:], uint64(len(data)))
+	if _, err := rw.w.Write(lenBuf[:n]); err != nil {
+		return errors.Wrap(err, "refstream: write length prefix")
+	}
+	if _, err := rw.w.Write(data); err != nil {
+		return errors.Wrap(err, "refstream: write ref")
+	}
+	return nil
+}
+
+// RefStreamReader reads back refs framed by a RefStreamWriter. maxSize
+// bounds a single frame's declared length, so a hostile or corrupt stream
+// can't make ReadRef allocate an unbounded buffer.
+type RefStreamReader struct {
+	r       *bufio.Reader
+	maxSize int64
+}
+
+// NewRefStreamReader returns a RefStreamReader reading framed refs from r,
+// rejecting any frame whose declared length exceeds maxSize.
+func NewRefStreamReader(r io.Reader, maxSize int64) *RefStreamReader {
+	return &RefStreamReader{r: bufio.NewReader(r), maxSize: maxSize}
+}
+
+// ReadRef reads and unmarshals the next framed ref. It returns io.EOF
+// (unwrapped) when the stream ends cleanly on a frame boundary.
+func (rr *RefStreamReader) ReadRef() (BinaryRef, error) {
+	var ref BinaryRef
+
+	n, err := binary.ReadUvarint(rr.r)
+	if err != nil {
+		if err == io.EOF {
+			return ref, io.EOF
+		}
+		return ref, errors.Wrap(err, "refstream: read length prefix")
+	}
+	if int64(n) > rr.maxSize {
+		return ref, errors.Errorf("refstream: frame of %d bytes exceeds max %d", n, rr.maxSize)
+	}
+
+	data := make([]byte, n)
+	if _, err := io.ReadFull(rr.r, data); err != nil {
+		return ref, errors.Wrap(err, "refstream: read ref")
+	}
+
+	if err := ref.UnmarshalBinary(data); err != nil {
+		return ref, errors.Wrap(err, "refstream: unmarshal ref")
+	}
+	return ref, nil
+}
@@ -0,0 +1,139 @@
+package gabbygrove
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/ugorji/go/codec"
+	"golang.org/x/crypto/ed25519"
+
+	refs "go.mindeco.de/ssb-refs"
+)
+
+func testRefs(t testing.TB, n int) []BinaryRef {
+	t.Helper()
+	out := make([]BinaryRef, n)
+	for i := range out {
+		pub, _, err := ed25519.GenerateKey(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fr, err := refs.NewFeedRefFromBytes(pub, refs.RefAlgoFeedGabby)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ref, err := NewBinaryRef(fr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out[i] = ref
+	}
+	return out
+}
+
+func TestRefStreamRoundtrip(t *testing.T) {
+	want := testRefs(t, 10)
+
+	var buf bytes.Buffer
+	w := NewRefStreamWriter(&buf)
+	for _, ref := range want {
+		if err := w.WriteRef(ref); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := NewRefStreamReader(&buf, 1024)
+	for i, wantRef := range want {
+		got, err := r.ReadRef()
+		if err != nil {
+			t.Fatalf("ReadRef %d: %v", i, err)
+		}
+		if got.Ref() != wantRef.Ref() {
+			t.Errorf("ref %d mismatch: got %q want %q", i, got.Ref(), wantRef.Ref())
+		}
+	}
+
+	if _, err := r.ReadRef(); err != io.EOF {
+		t.Errorf("expected io.EOF at end of stream, got %v", err)
+	}
+}
+
+func TestRefStreamReaderRejectsOversizedFrame(t *testing.T) {
+	want := testRefs(t, 1)
+
+	var buf bytes.Buffer
+	if err := NewRefStreamWriter(&buf).WriteRef(want[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewRefStreamReader(&buf, 4) // smaller than any real ref
+	if _, err := r.ReadRef(); err == nil {
+		t.Fatal("expected an error for a frame exceeding maxSize, got nil")
+	}
+}
+
+// cborArrayHandle encodes []BinaryRef as a plain CBOR array via BinRefExt,
+// the alternative to the length-prefixed stream format that predates it.
+func cborArrayHandle() *codec.CborHandle {
+	h := &codec.CborHandle{}
+	if err := h.SetInterfaceExt(reflect.TypeOf(BinaryRef{}), 1, BinRefExt{}); err != nil {
+		panic(err)
+	}
+	return h
+}
+
+func encodeCBORArray(refs []BinaryRef) ([]byte, error) {
+	var out []byte
+	enc := codec.NewEncoderBytes(&out, cborArrayHandle())
+	err := enc.Encode(refs)
+	return out, err
+}
+
+func decodeCBORArray(data []byte, n int) ([]BinaryRef, error) {
+	out := make([]BinaryRef, n)
+	dec := codec.NewDecoderBytes(data, cborArrayHandle())
+	err := dec.Decode(&out)
+	return out, err
+}
+
+// BenchmarkRefStream compares the varint-framed streaming codec against
+// wrapping the same refs in a CBOR array via BinRefExt.
+func BenchmarkRefStream(b *testing.B) {
+	const n = 100
+	refSet := testRefs(b, n)
+
+	b.Run("stream", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var buf bytes.Buffer
+			w := NewRefStreamWriter(&buf)
+			for _, ref := range refSet {
+				if err := w.WriteRef(ref); err != nil {
+					b.Fatal(err)
+				}
+			}
+
+			r := NewRefStreamReader(&buf, 1024)
+			for j := 0; j < n; j++ {
+				if _, err := r.ReadRef(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+
+	b.Run("cbor_array", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			data, err := encodeCBORArray(refSet)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if _, err := decodeCBORArray(data, n); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
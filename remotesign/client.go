@@ -0,0 +1,145 @@
+package remotesign
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ed25519"
+
+	gabbygrove "go.mindeco.de/gabbygrove"
+)
+
+// RequestTimeout bounds how long a single round-trip may take before the
+// client gives up and drops the connection; the next call redials.
+const RequestTimeout = 5 * time.Second
+
+// initialBackoff and maxBackoff bound the reconnect loop's exponential
+// backoff between dial attempts.
+const (
+	initialBackoff = 100 * time.Millisecond
+	maxBackoff     = 10 * time.Second
+)
+
+// RemoteSigner is a crypto.Signer-like client for a feed whose private key
+// lives behind a Server, reachable over addr. It satisfies the signing
+// interface gabbygrove.Publisher expects from a local ed25519.PrivateKey:
+// PublicKey() and Sign(message []byte) ([]byte, error).
+type RemoteSigner struct {
+	network, addr string
+	feed          gabbygrove.BinaryRef
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// NewRemoteSigner returns a client for the signer listening on (network,
+// addr) that acts for feed. The connection is established lazily on first
+// use. After a failed request the connection is dropped and the error is
+// returned; the request is not retried, and the next call redials.
+func NewRemoteSigner(network, addr string, feed gabbygrove.BinaryRef) *RemoteSigner {
+	return &RemoteSigner{network: network, addr: addr, feed: feed}
+}
+
+// PublicKey asks the remote signer for feed's public key.
+func (rs *RemoteSigner) PublicKey() (ed25519.PublicKey, error) {
+	var resp PubKeyResponse
+	if err := rs.roundTrip(kindPubKeyRequest, PubKeyRequest{FeedRef: rs.feed}, kindPubKeyResponse, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Err != "" {
+		return nil, errors.New(resp.Err)
+	}
+	return resp.Key, nil
+}
+
+// Sign asks the remote signer to sign message as feed.
+func (rs *RemoteSigner) Sign(message []byte) ([]byte, error) {
+	var resp SignResponse
+	req := SignRequest{FeedRef: rs.feed, Payload: message}
+	if err := rs.roundTrip(kindSignRequest, req, kindSignResponse, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Err != "" {
+		return nil, errors.New(resp.Err)
+	}
+	return resp.Sig, nil
+}
+
+// Ping checks that the signer is reachable and speaking the protocol.
+func (rs *RemoteSigner) Ping() error {
+	return rs.roundTrip(kindPing, Ping{}, kindPing, &Ping{})
+}
+
+// Close drops the underlying connection, if any.
+func (rs *RemoteSigner) Close() error {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	if rs.conn == nil {
+		return nil
+	}
+	err := rs.conn.Close()
+	rs.conn = nil
+	return err
+}
+
+func (rs *RemoteSigner) roundTrip(reqKind msgKind, req interface{}, wantKind msgKind, resp interface{}) error {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	conn, err := rs.connLocked()
+	if err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(RequestTimeout)
+	_ = conn.SetDeadline(deadline)
+
+	if err := writeFrame(conn, reqKind, req); err != nil {
+		rs.dropLocked()
+		return err
+	}
+
+	kind, err := readFrame(conn, resp)
+	if err != nil {
+		rs.dropLocked()
+		return err
+	}
+	if kind != wantKind {
+		rs.dropLocked()
+		return errors.Errorf("remotesign: unexpected response kind: %d (wanted %d)", kind, wantKind)
+	}
+	return nil
+}
+
+// connLocked returns the current connection, (re)dialing with exponential
+// backoff if it is not yet established. rs.mu must be held.
+func (rs *RemoteSigner) connLocked() (net.Conn, error) {
+	if rs.conn != nil {
+		return rs.conn, nil
+	}
+
+	backoff := initialBackoff
+	var lastErr error
+	for attempt := 0; attempt < 10; attempt++ {
+		conn, err := net.DialTimeout(rs.network, rs.addr, RequestTimeout)
+		if err == nil {
+			rs.conn = conn
+			return conn, nil
+		}
+		lastErr = err
+		time.Sleep(backoff)
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+	return nil, errors.Wrap(lastErr, "remotesign: could not reach signer")
+}
+
+func (rs *RemoteSigner) dropLocked() {
+	if rs.conn != nil {
+		rs.conn.Close()
+		rs.conn = nil
+	}
+}
@@ -0,0 +1,152 @@
+// Package remotesign lets the ed25519 signing a gabbygrove.Publisher needs
+// be delegated to an out-of-process signer, keyed by feed id. The protocol
+// is deliberately small and is modeled on Tendermint's privval remote
+// signer: a length-prefixed, CBOR-framed request/response exchange over any
+// net.Conn.
+package remotesign
+
+import (
+	"encoding/binary"
+	"io"
+	"reflect"
+
+	"github.com/pkg/errors"
+	"github.com/ugorji/go/codec"
+	"golang.org/x/crypto/ed25519"
+
+	gabbygrove "go.mindeco.de/gabbygrove"
+)
+
+// msgKind tags the payload that follows a frame's length prefix, since CBOR
+// alone doesn't tell a reader which Go type to decode into.
+type msgKind uint8
+
+const (
+	kindPing msgKind = iota + 1
+	kindPubKeyRequest
+	kindPubKeyResponse
+	kindSignRequest
+	kindSignResponse
+)
+
+// maxFrameSize bounds a single frame so a misbehaving peer can't make us
+// allocate an unbounded buffer.
+const maxFrameSize = 1 << 20 // 1MiB, well above any single event
+
+// Ping is a liveness check; the callee is expected to echo it back.
+type Ping struct{}
+
+// PubKeyRequest asks the signer for the public key of FeedRef.
+type PubKeyRequest struct {
+	FeedRef gabbygrove.BinaryRef
+}
+
+// PubKeyResponse carries the requested public key, or Err if the feed isn't
+// on the server's allow-list.
+type PubKeyResponse struct {
+	Key ed25519.PublicKey
+	Err string
+}
+
+// SignRequest asks the signer to sign Payload as FeedRef.
+type SignRequest struct {
+	FeedRef gabbygrove.BinaryRef
+	Payload []byte
+}
+
+// SignResponse carries the signature, or Err if FeedRef isn't on the
+// server's allow-list or signing otherwise failed.
+type SignResponse struct {
+	Sig []byte
+	Err string
+}
+
+var handle = &codec.CborHandle{}
+
+func init() {
+	if err := handle.SetInterfaceExt(reflect.TypeOf(gabbygrove.BinaryRef{}), 1, gabbygrove.BinRefExt{}); err != nil {
+		panic(err)
+	}
+}
+
+// writeFrame CBOR-encodes msg and writes it as one frame: a single raw
+// kind byte, a big-endian uint32 byte count of the body, then the body.
+func writeFrame(w io.Writer, kind msgKind, msg interface{}) error {
+	var body []byte
+	enc := codec.NewEncoderBytes(&body, handle)
+	if err := enc.Encode(msg); err != nil {
+		return errors.Wrap(err, "remotesign: encode frame body")
+	}
+
+	frame := make([]byte, 5+len(body))
+	frame[0] = byte(kind)
+	binary.BigEndian.PutUint32(frame[1:5], uint32(len(body)))
+	copy(frame[5:], body)
+
+	_, err := w.Write(frame)
+	return errors.Wrap(err, "remotesign: write frame")
+}
+
+// readFrame reads one length-prefixed frame and decodes its body into msg,
+// returning the frame's kind tag.
+func readFrame(r io.Reader, msg interface{}) (msgKind, error) {
+	var hdr [5]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return 0, errors.Wrap(err, "remotesign: read frame header")
+	}
+	kind := msgKind(hdr[0])
+	n := binary.BigEndian.Uint32(hdr[1:])
+	if n > maxFrameSize {
+		return 0, errors.Errorf("remotesign: frame too large: %d", n)
+	}
+
+	body := make([]byte, n)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, errors.Wrap(err, "remotesign: read frame body")
+	}
+
+	dec := codec.NewDecoderBytes(body, handle)
+	if err := dec.Decode(msg); err != nil {
+		return 0, errors.Wrap(err, "remotesign: decode frame body")
+	}
+	return kind, nil
+}
+
+// readAnyFrame reads one frame whose kind isn't known ahead of time,
+// decoding its body into the matching request/Ping type and returning it
+// alongside the kind tag. Used by Server, which must handle whichever
+// request a client happens to send next.
+func readAnyFrame(r io.Reader) (msgKind, interface{}, error) {
+	var hdr [5]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return 0, nil, errors.Wrap(err, "remotesign: read frame header")
+	}
+	kind := msgKind(hdr[0])
+	n := binary.BigEndian.Uint32(hdr[1:])
+	if n > maxFrameSize {
+		return 0, nil, errors.Errorf("remotesign: frame too large: %d", n)
+	}
+
+	body := make([]byte, n)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, nil, errors.Wrap(err, "remotesign: read frame body")
+	}
+
+	var msg interface{}
+	switch kind {
+	case kindPing:
+		msg = &Ping{}
+	case kindPubKeyRequest:
+		msg = &PubKeyRequest{}
+	case kindSignRequest:
+		msg = &SignRequest{}
+	default:
+		return 0, nil, errors.Errorf("remotesign: unknown request kind: %d", kind)
+	}
+
+	dec := codec.NewDecoderBytes(body, handle)
+	if err := dec.Decode(msg); err != nil {
+		return 0, nil, errors.Wrap(err, "remotesign: decode frame body")
+	}
+	return kind, msg, nil
+}
@@ -0,0 +1,125 @@
+package remotesign
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/ed25519"
+
+	gabbygrove "go.mindeco.de/gabbygrove"
+	refs "go.mindeco.de/ssb-refs"
+)
+
+func TestRemoteSignerOverUnixSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "remotesign-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	sockPath := filepath.Join(dir, "signer.sock")
+
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fr, err := refs.NewFeedRefFromBytes(pub, refs.RefAlgoFeedGabby)
+	if err != nil {
+		t.Fatal(err)
+	}
+	feed, err := gabbygrove.NewBinaryRef(fr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := NewServer()
+	srv.Allow(feed, priv)
+
+	ln, err := net.Listen("unix", sockPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go srv.Serve(ln)
+
+	client := NewRemoteSigner("unix", sockPath, feed)
+	defer client.Close()
+
+	if err := client.Ping(); err != nil {
+		t.Fatalf("ping: %v", err)
+	}
+
+	gotPub, err := client.PublicKey()
+	if err != nil {
+		t.Fatalf("PublicKey: %v", err)
+	}
+	if !gotPub.Equal(pub) {
+		t.Errorf("public key mismatch: got %x want %x", gotPub, pub)
+	}
+
+	// Sign a few payloads remotely and verify each signature locally.
+	for i := 0; i < 3; i++ {
+		payload := []byte{byte(i), byte(i), byte(i)}
+		sig, err := client.Sign(payload)
+		if err != nil {
+			t.Fatalf("event %d: Sign: %v", i, err)
+		}
+		if !ed25519.Verify(pub, payload, sig) {
+			t.Fatalf("event %d: signature did not verify", i)
+		}
+	}
+}
+
+func TestRemoteSignerRejectsUnknownFeed(t *testing.T) {
+	dir, err := ioutil.TempDir("", "remotesign-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	sockPath := filepath.Join(dir, "signer.sock")
+
+	_, allowedPriv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	allowedFR, err := refs.NewFeedRefFromBytes(allowedPriv.Public().(ed25519.PublicKey), refs.RefAlgoFeedGabby)
+	if err != nil {
+		t.Fatal(err)
+	}
+	allowedFeed, err := gabbygrove.NewBinaryRef(allowedFR)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := NewServer()
+	srv.Allow(allowedFeed, allowedPriv)
+
+	ln, err := net.Listen("unix", sockPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go srv.Serve(ln)
+
+	otherPub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherFR, err := refs.NewFeedRefFromBytes(otherPub, refs.RefAlgoFeedGabby)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherFeed, err := gabbygrove.NewBinaryRef(otherFR)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := NewRemoteSigner("unix", sockPath, otherFeed)
+	defer client.Close()
+
+	if _, err := client.Sign([]byte("hello")); err == nil {
+		t.Fatal("expected an error signing for a feed not on the allow-list")
+	}
+}
@@ -0,0 +1,98 @@
+package remotesign
+
+import (
+	"net"
+	"sync"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ed25519"
+
+	gabbygrove "go.mindeco.de/gabbygrove"
+)
+
+// Server holds private key material for one or more feeds and answers
+// PubKeyRequest/SignRequest frames over accepted connections. Only feeds
+// passed to Allow are served; everyone else gets an error string in the
+// response's Err field instead of a signature.
+type Server struct {
+	mu   sync.Mutex
+	keys map[string]ed25519.PrivateKey
+}
+
+// NewServer returns a Server with an empty allow-list.
+func NewServer() *Server {
+	return &Server{keys: make(map[string]ed25519.PrivateKey)}
+}
+
+// Allow adds feed to the allow-list, serviced with priv.
+func (s *Server) Allow(feed gabbygrove.BinaryRef, priv ed25519.PrivateKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[feed.Ref()] = priv
+}
+
+func (s *Server) lookup(feed gabbygrove.BinaryRef) (ed25519.PrivateKey, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	priv, ok := s.keys[feed.Ref()]
+	return priv, ok
+}
+
+// Serve accepts connections on ln until it is closed, handling each on its
+// own goroutine.
+func (s *Server) Serve(ln net.Listener) error {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return errors.Wrap(err, "remotesign: accept")
+		}
+		go s.handleConn(conn)
+	}
+}
+
+func (s *Server) handleConn(conn net.Conn) {
+	defer conn.Close()
+	for {
+		kind, msg, err := readAnyFrame(conn)
+		if err != nil {
+			return
+		}
+
+		switch kind {
+		case kindPing:
+			if err := writeFrame(conn, kindPing, Ping{}); err != nil {
+				return
+			}
+
+		case kindPubKeyRequest:
+			req := msg.(*PubKeyRequest)
+			resp := PubKeyResponse{}
+			priv, ok := s.lookup(req.FeedRef)
+			if !ok {
+				resp.Err = "remotesign: feed not on allow-list"
+			} else {
+				resp.Key = priv.Public().(ed25519.PublicKey)
+			}
+			if err := writeFrame(conn, kindPubKeyResponse, resp); err != nil {
+				return
+			}
+
+		case kindSignRequest:
+			req := msg.(*SignRequest)
+			resp := SignResponse{}
+			priv, ok := s.lookup(req.FeedRef)
+			if !ok {
+				resp.Err = "remotesign: feed not on allow-list"
+			} else {
+				resp.Sig = ed25519.Sign(priv, req.Payload)
+			}
+			if err := writeFrame(conn, kindSignResponse, resp); err != nil {
+				return
+			}
+
+		default:
+			// unknown frame kind: drop the connection rather than guess
+			return
+		}
+	}
+}
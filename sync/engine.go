@@ -0,0 +1,231 @@
+package sync
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	gabbygrove "go.mindeco.de/gabbygrove"
+)
+
+// defaultOfferInterval is how often an Engine batches up and sends OFFER
+// frames for the feeds it knows about.
+const defaultOfferInterval = 5 * time.Second
+
+// defaultRetransmitInitial is the delay before a REQUEST is first resent if
+// no matching MESSAGE has arrived. It doubles every epoch, up to
+// defaultRetransmitMaxEpoch times.
+const defaultRetransmitInitial = 2 * time.Second
+
+// defaultRetransmitMaxEpoch caps how many times a REQUEST's retransmit
+// delay is doubled, so a permanently missing message is retried forever at
+// a bounded rate rather than abandoned or hammered.
+const defaultRetransmitMaxEpoch = 6
+
+// pendingRequest tracks a ref we've asked for but not yet received.
+type pendingRequest struct {
+	feed, ref gabbygrove.BinaryRef
+	epoch     int
+	dueAt     time.Time
+}
+
+// Engine runs MVDS-style set reconciliation for a set of feeds against a
+// Store, exchanging Frames with peers over a Transport.
+type Engine struct {
+	store     Store
+	transport Transport
+	peers     []string
+
+	retransmitInitial  time.Duration
+	retransmitMaxEpoch int
+	offerInterval      time.Duration
+
+	mu      sync.Mutex
+	feeds   []gabbygrove.BinaryRef
+	seen    *lru
+	pending map[string]*pendingRequest
+}
+
+// NewEngine returns an Engine that syncs feeds against store, talking to
+// peers over transport.
+func NewEngine(store Store, transport Transport, peers []string, feeds []gabbygrove.BinaryRef, opts ...EngineOption) *Engine {
+	e := &Engine{
+		store:              store,
+		transport:          transport,
+		peers:              peers,
+		feeds:              feeds,
+		retransmitInitial:  defaultRetransmitInitial,
+		retransmitMaxEpoch: defaultRetransmitMaxEpoch,
+		offerInterval:      defaultOfferInterval,
+		seen:               newLRU(0),
+		pending:            make(map[string]*pendingRequest),
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// Run drives the Engine until ctx is cancelled: periodically broadcasting
+// OFFERs, retransmitting overdue REQUESTs, and processing inbound Frames.
+func (e *Engine) Run(ctx context.Context) error {
+	offerTicker := time.NewTicker(e.offerInterval)
+	defer offerTicker.Stop()
+
+	retransmitTicker := time.NewTicker(e.retransmitInitial)
+	defer retransmitTicker.Stop()
+
+	// Send an initial round of OFFERs right away rather than waiting out a
+	// full offerInterval, so a freshly started Engine doesn't sit idle.
+	e.broadcastOffers()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case <-offerTicker.C:
+			e.broadcastOffers()
+
+		case <-retransmitTicker.C:
+			e.retransmitOverdue()
+
+		case pf, ok := <-e.transport.Frames():
+			if !ok {
+				return errors.New("sync: transport closed")
+			}
+			e.handleFrame(pf)
+		}
+	}
+}
+
+func (e *Engine) broadcastOffers() {
+	e.mu.Lock()
+	feeds := append([]gabbygrove.BinaryRef(nil), e.feeds...)
+	e.mu.Unlock()
+
+	for _, feed := range feeds {
+		frame := Frame{Feed: feed, Offers: e.store.Refs(feed)}
+		e.sendToAll(frame)
+	}
+}
+
+func (e *Engine) retransmitOverdue() {
+	now := time.Now()
+
+	e.mu.Lock()
+	var due []pendingRequest
+	for _, p := range e.pending {
+		if now.Before(p.dueAt) {
+			continue
+		}
+		if p.epoch < e.retransmitMaxEpoch {
+			p.epoch++
+		}
+		p.dueAt = now.Add(e.retransmitInitial * (1 << uint(p.epoch)))
+		due = append(due, *p)
+	}
+	e.mu.Unlock()
+
+	for _, p := range due {
+		e.sendToAll(Frame{Feed: p.feed, Requests: []gabbygrove.BinaryRef{p.ref}})
+	}
+}
+
+func (e *Engine) handleFrame(pf PeerFrame) {
+	frame := pf.Frame
+
+	if len(frame.Offers) > 0 {
+		e.handleOffers(frame.Feed, pf.Peer, frame.Offers)
+	}
+	if len(frame.Requests) > 0 {
+		e.handleRequests(frame.Feed, pf.Peer, frame.Requests)
+	}
+	if len(frame.Messages) > 0 {
+		e.handleMessages(frame.Feed, frame.Messages)
+	}
+}
+
+// handleOffers diffs an OFFER against our log and asks for whatever's
+// missing.
+func (e *Engine) handleOffers(feed gabbygrove.BinaryRef, peer string, offers []gabbygrove.BinaryRef) {
+	var want []gabbygrove.BinaryRef
+
+	e.mu.Lock()
+	for _, ref := range offers {
+		if e.store.Has(feed, ref) {
+			continue
+		}
+		key := requestKey(feed, ref)
+		if _, already := e.pending[key]; already {
+			continue
+		}
+		e.pending[key] = &pendingRequest{
+			feed:  feed,
+			ref:   ref,
+			dueAt: time.Now().Add(e.retransmitInitial),
+		}
+		want = append(want, ref)
+	}
+	e.mu.Unlock()
+
+	if len(want) > 0 {
+		e.send(peer, Frame{Feed: feed, Requests: want})
+	}
+}
+
+// handleRequests answers a REQUEST with the full payload for every ref we
+// actually have.
+func (e *Engine) handleRequests(feed gabbygrove.BinaryRef, peer string, refs []gabbygrove.BinaryRef) {
+	var msgs []Message
+	for _, ref := range refs {
+		if !e.store.Has(feed, ref) {
+			continue
+		}
+		payload, err := e.store.Get(feed, ref)
+		if err != nil {
+			continue
+		}
+		msgs = append(msgs, Message{Ref: ref, Payload: payload})
+	}
+	if len(msgs) > 0 {
+		e.send(peer, Frame{Feed: feed, Messages: msgs})
+	}
+}
+
+// handleMessages verifies and appends incoming events, deduplicating
+// against the seen-cache and clearing any matching pending REQUEST.
+func (e *Engine) handleMessages(feed gabbygrove.BinaryRef, msgs []Message) {
+	for _, m := range msgs {
+		ref := m.Ref
+		key := requestKey(feed, ref)
+
+		e.mu.Lock()
+		alreadySeen := e.seen.SeenBefore(key)
+		delete(e.pending, key)
+		e.mu.Unlock()
+
+		if alreadySeen || e.store.Has(feed, ref) {
+			continue
+		}
+		if err := e.store.Append(feed, ref, m.Payload); err != nil {
+			continue
+		}
+	}
+}
+
+func (e *Engine) sendToAll(frame Frame) {
+	for _, peer := range e.peers {
+		e.send(peer, frame)
+	}
+}
+
+func (e *Engine) send(peer string, frame Frame) {
+	_ = e.transport.Send(peer, frame)
+}
+
+func requestKey(feed, ref gabbygrove.BinaryRef) string {
+	return feed.Ref() + "|" + ref.Ref()
+}
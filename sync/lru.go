@@ -0,0 +1,43 @@
+package sync
+
+import "container/list"
+
+// lru is a fixed-capacity, least-recently-used set of ref keys, used to
+// deduplicate MESSAGE frames we've already applied so a replay doesn't
+// re-verify and re-append the same event.
+type lru struct {
+	cap   int
+	items map[string]*list.Element
+	order *list.List // front = most recently seen
+}
+
+func newLRU(capacity int) *lru {
+	if capacity <= 0 {
+		capacity = 4096
+	}
+	return &lru{
+		cap:   capacity,
+		items: make(map[string]*list.Element, capacity),
+		order: list.New(),
+	}
+}
+
+// SeenBefore reports whether key was already recorded, and records it.
+func (c *lru) SeenBefore(key string) bool {
+	if el, ok := c.items[key]; ok {
+		c.order.MoveToFront(el)
+		return true
+	}
+
+	el := c.order.PushFront(key)
+	c.items[key] = el
+
+	if c.order.Len() > c.cap {
+		oldest := c.order.Back()
+		if oldest != nil {
+			c.order.Remove(oldest)
+			delete(c.items, oldest.Value.(string))
+		}
+	}
+	return false
+}
@@ -0,0 +1,111 @@
+package sync
+
+import (
+	"crypto/sha256"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	gabbygrove "go.mindeco.de/gabbygrove"
+	refs "go.mindeco.de/ssb-refs"
+)
+
+// MemStore is a trivial, order-preserving Store backed by a map, useful for
+// tests and as a reference implementation to wrap a real log with.
+type MemStore struct {
+	mu   sync.Mutex
+	refs map[string][]gabbygrove.BinaryRef
+	logs map[string]map[string][]byte
+}
+
+// NewMemStore returns an empty MemStore.
+func NewMemStore() *MemStore {
+	return &MemStore{
+		refs: make(map[string][]gabbygrove.BinaryRef),
+		logs: make(map[string]map[string][]byte),
+	}
+}
+
+// Put seeds feed's log with ref/payload directly, bypassing Engine - useful
+// to give one node in a test a head start.
+func (s *MemStore) Put(feed, ref gabbygrove.BinaryRef, payload []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.appendLocked(feed, ref, payload)
+}
+
+func (s *MemStore) Has(feed, ref gabbygrove.BinaryRef) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	log, ok := s.logs[feed.Ref()]
+	if !ok {
+		return false
+	}
+	_, ok = log[ref.Ref()]
+	return ok
+}
+
+func (s *MemStore) Refs(feed gabbygrove.BinaryRef) []gabbygrove.BinaryRef {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]gabbygrove.BinaryRef(nil), s.refs[feed.Ref()]...)
+}
+
+func (s *MemStore) Get(feed, ref gabbygrove.BinaryRef) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	log, ok := s.logs[feed.Ref()]
+	if !ok {
+		return nil, errors.Errorf("sync: unknown feed %q", feed.Ref())
+	}
+	payload, ok := log[ref.Ref()]
+	if !ok {
+		return nil, errors.Errorf("sync: unknown ref %q", ref.Ref())
+	}
+	return payload, nil
+}
+
+// Append implements Store.Append, rejecting any payload whose SHA-256
+// doesn't match ref - the same derivation testMessage/RegisterRefAlgo use
+// for RefTypeMessage - so a peer can't smuggle an arbitrary payload in
+// under someone else's ref.
+func (s *MemStore) Append(feed, ref gabbygrove.BinaryRef, payload []byte) error {
+	if err := verifyMessageRef(ref, payload); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.appendLocked(feed, ref, payload)
+	return nil
+}
+
+// verifyMessageRef recomputes the message ref for payload and checks it
+// against ref, returning an error on mismatch.
+func verifyMessageRef(ref gabbygrove.BinaryRef, payload []byte) error {
+	h := sha256.Sum256(payload)
+	want, err := refs.NewMessageRefFromBytes(h[:], refs.RefAlgoMessageGabby)
+	if err != nil {
+		return errors.Wrap(err, "sync: deriving message ref")
+	}
+	wantRef, err := gabbygrove.NewBinaryRef(want)
+	if err != nil {
+		return errors.Wrap(err, "sync: deriving message ref")
+	}
+	if wantRef.Ref() != ref.Ref() {
+		return errors.Errorf("sync: payload does not match ref %q (got %q)", ref.Ref(), wantRef.Ref())
+	}
+	return nil
+}
+
+func (s *MemStore) appendLocked(feed, ref gabbygrove.BinaryRef, payload []byte) {
+	log, ok := s.logs[feed.Ref()]
+	if !ok {
+		log = make(map[string][]byte)
+		s.logs[feed.Ref()] = log
+	}
+	if _, already := log[ref.Ref()]; already {
+		return
+	}
+	log[ref.Ref()] = payload
+	s.refs[feed.Ref()] = append(s.refs[feed.Ref()], ref)
+}
@@ -0,0 +1,91 @@
+// Package sync implements MVDS-style (Minimum Viable Data Sync) set
+// reconciliation between gabby-grove feeds, borrowing the datasync layer
+// design from status-protocol-go. Each peer keeps three per-feed sets -
+// OFFER (refs we have), REQUEST (refs we want) and MESSAGE (payloads in
+// flight) - and exchanges batched Frames containing any subset of them.
+package sync
+
+import (
+	"time"
+
+	gabbygrove "go.mindeco.de/gabbygrove"
+)
+
+// Message is a single logged event in flight: its ref (so the receiver
+// doesn't have to re-derive or trust a hash embedded in Payload) and its
+// wire bytes. gabbygrove has no exported Transfer/event type yet, so Store
+// and Frame deal in these opaque, ref-addressed payloads rather than a
+// concrete event type; a Store implementation is expected to know how to
+// decode and verify Payload for its own log format.
+type Message struct {
+	Ref     gabbygrove.BinaryRef
+	Payload []byte
+}
+
+// Frame is the unit exchanged between two peers. A single Frame may carry
+// any combination of offers, requests and messages; callers are expected to
+// batch what's pending rather than send one Frame per ref.
+type Frame struct {
+	Feed     gabbygrove.BinaryRef
+	Offers   []gabbygrove.BinaryRef
+	Requests []gabbygrove.BinaryRef
+	Messages []Message
+}
+
+// Store abstracts the feed log an Engine syncs against.
+type Store interface {
+	// Has reports whether ref is already appended to feed's log.
+	Has(feed, ref gabbygrove.BinaryRef) bool
+
+	// Refs returns every ref currently in feed's log, in log order.
+	Refs(feed gabbygrove.BinaryRef) []gabbygrove.BinaryRef
+
+	// Get returns the wire bytes previously appended for ref.
+	Get(feed, ref gabbygrove.BinaryRef) ([]byte, error)
+
+	// Append verifies that payload's ref is indeed ref and appends it to
+	// feed's log.
+	Append(feed, ref gabbygrove.BinaryRef, payload []byte) error
+}
+
+// Transport abstracts the wire a Frame rides on, so an Engine can run over
+// muxrpc, QUIC, or an in-memory channel for tests.
+type Transport interface {
+	// Send delivers frame to peer. peer identifies a remote node in
+	// whatever namespace the Transport implementation uses.
+	Send(peer string, frame Frame) error
+
+	// Frames yields Frames as they arrive from any peer.
+	Frames() <-chan PeerFrame
+}
+
+// PeerFrame pairs an inbound Frame with the peer it came from.
+type PeerFrame struct {
+	Peer  string
+	Frame Frame
+}
+
+// EngineOption configures an Engine at construction time.
+type EngineOption func(*Engine)
+
+// WithRetransmit overrides the default retransmit schedule.
+func WithRetransmit(initial time.Duration, maxEpoch int) EngineOption {
+	return func(e *Engine) {
+		e.retransmitInitial = initial
+		e.retransmitMaxEpoch = maxEpoch
+	}
+}
+
+// WithSeenCacheSize overrides the default size of the dedupe LRU.
+func WithSeenCacheSize(n int) EngineOption {
+	return func(e *Engine) {
+		e.seen = newLRU(n)
+	}
+}
+
+// WithOfferInterval overrides how often OFFER frames are broadcast.
+func WithOfferInterval(d time.Duration) EngineOption {
+	return func(e *Engine) {
+		e.offerInterval = d
+	}
+}
@@ -0,0 +1,130 @@
+package sync
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ed25519"
+
+	gabbygrove "go.mindeco.de/gabbygrove"
+	refs "go.mindeco.de/ssb-refs"
+)
+
+func testFeed(t *testing.T) gabbygrove.BinaryRef {
+	t.Helper()
+	pub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fr, err := refs.NewFeedRefFromBytes(pub, refs.RefAlgoFeedGabby)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref, err := gabbygrove.NewBinaryRef(fr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ref
+}
+
+// testMessage returns the message ref for payload, i.e. its SHA-256, which
+// is all a MemStore needs - it doesn't verify signatures.
+func testMessage(t *testing.T, payload []byte) gabbygrove.BinaryRef {
+	t.Helper()
+	h := sha256.Sum256(payload)
+	mr, err := refs.NewMessageRefFromBytes(h[:], refs.RefAlgoMessageGabby)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref, err := gabbygrove.NewBinaryRef(mr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ref
+}
+
+func TestMemStoreAppendRejectsMismatchedPayload(t *testing.T) {
+	feed := testFeed(t)
+	store := NewMemStore()
+
+	ref := testMessage(t, []byte("the real payload"))
+	if err := store.Append(feed, ref, []byte("a different payload")); err == nil {
+		t.Fatal("expected Append to reject a payload that doesn't hash to ref, got nil")
+	}
+	if store.Has(feed, ref) {
+		t.Fatal("Append should not have stored the mismatched payload")
+	}
+}
+
+// TestThreeNodeConvergence seeds node A with every message, leaves B and C
+// empty, and checks that all three end up holding all nMessages messages.
+// It compares message counts only; the order of each node's log, and so
+// its last ref, is not compared.
+func TestThreeNodeConvergence(t *testing.T) {
+	const nMessages = 20
+
+	feed := testFeed(t)
+	hub := NewMemHub()
+
+	names := []string{"a", "b", "c"}
+	stores := map[string]*MemStore{}
+	transports := map[string]Transport{}
+	for _, n := range names {
+		stores[n] = NewMemStore()
+		transports[n] = NewMemTransport(hub, n)
+	}
+
+	for i := 0; i < nMessages; i++ {
+		payload := []byte(fmt.Sprintf("message-%d", i))
+		ref := testMessage(t, payload)
+		stores["a"].Put(feed, ref, payload)
+	}
+
+	peersOf := func(self string) []string {
+		var peers []string
+		for _, n := range names {
+			if n != self {
+				peers = append(peers, n)
+			}
+		}
+		return peers
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for _, n := range names {
+		e := NewEngine(stores[n], transports[n], peersOf(n), []gabbygrove.BinaryRef{feed},
+			WithRetransmit(20*time.Millisecond, 4),
+			WithOfferInterval(50*time.Millisecond))
+		go e.Run(ctx)
+	}
+
+	deadline := time.After(5 * time.Second)
+	tick := time.NewTicker(10 * time.Millisecond)
+	defer tick.Stop()
+
+	for {
+		select {
+		case <-deadline:
+			for _, n := range names {
+				t.Errorf("node %s has %d/%d messages", n, len(stores[n].Refs(feed)), nMessages)
+			}
+			t.Fatal("nodes did not converge in time")
+		case <-tick.C:
+			converged := true
+			for _, n := range names {
+				if len(stores[n].Refs(feed)) != nMessages {
+					converged = false
+					break
+				}
+			}
+			if converged {
+				return
+			}
+		}
+	}
+}
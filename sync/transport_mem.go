@@ -0,0 +1,55 @@
+package sync
+
+import "github.com/pkg/errors"
+
+// MemTransport is an in-process Transport backed by Go channels, for wiring
+// up Engines in tests without a real network.
+type MemTransport struct {
+	self string
+	hub  *memHub
+	in   chan PeerFrame
+}
+
+// memHub fans frames sent by any MemTransport out to the named recipient.
+type memHub struct {
+	transports map[string]*MemTransport
+}
+
+// NewMemHub returns an empty hub. Register peers on it with NewMemTransport.
+func NewMemHub() *memHub {
+	return &memHub{transports: make(map[string]*MemTransport)}
+}
+
+// memTransportBuffer sizes each peer's inbox. Send drops a frame rather
+// than block when it's full - losing an OFFER or REQUEST is harmless here
+// since the Engine's periodic broadcast and retransmit schedule will
+// produce another one.
+const memTransportBuffer = 256
+
+// NewMemTransport registers a new peer named name on hub and returns its
+// Transport. Names must be unique within a hub.
+func NewMemTransport(hub *memHub, name string) *MemTransport {
+	t := &MemTransport{
+		self: name,
+		hub:  hub,
+		in:   make(chan PeerFrame, memTransportBuffer),
+	}
+	hub.transports[name] = t
+	return t
+}
+
+func (t *MemTransport) Send(peer string, frame Frame) error {
+	dst, ok := t.hub.transports[peer]
+	if !ok {
+		return errors.Errorf("sync: unknown peer %q", peer)
+	}
+	select {
+	case dst.in <- PeerFrame{Peer: t.self, Frame: frame}:
+	default:
+	}
+	return nil
+}
+
+func (t *MemTransport) Frames() <-chan PeerFrame {
+	return t.in
+}